@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/fsnotify/fsnotify"
+)
+
+// BacklinkIndex is an in-memory reverse-link graph: for every note, the
+// set of notes that link to it.
+type BacklinkIndex struct {
+	mu        sync.RWMutex
+	backlinks map[string]map[string]bool
+}
+
+// NewBacklinkIndex returns an empty BacklinkIndex.
+func NewBacklinkIndex() *BacklinkIndex {
+	return &BacklinkIndex{backlinks: make(map[string]map[string]bool)}
+}
+
+// add records that source links to target.
+func (idx *BacklinkIndex) add(target string, source string) {
+	if target == source {
+		return
+	}
+	if idx.backlinks[target] == nil {
+		idx.backlinks[target] = make(map[string]bool)
+	}
+	idx.backlinks[target][source] = true
+}
+
+// Backlinks returns the sorted-by-discovery set of notes that link to path.
+func (idx *BacklinkIndex) Backlinks(notePath string) []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	notePath = "/" + strings.TrimPrefix(notePath, "/")
+	sources := idx.backlinks[notePath]
+	result := make([]string, 0, len(sources))
+	for source := range sources {
+		result = append(result, source)
+	}
+	return result
+}
+
+// walkVFS recursively visits every file under dir in zkVFS, calling fn with
+// each file's path relative to the VFS root.
+func walkVFS(zkVFS VFS, dir string, fn func(filePath string) error) error {
+	entries, err := zkVFS.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		entryPath := path.Join(dir, entry.Name())
+		if entry.IsDir() {
+			if err := walkVFS(zkVFS, entryPath, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(entryPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BuildBacklinkIndex scans every HTML and Markdown note in zkVFS and
+// builds the reverse-link graph between them.
+func BuildBacklinkIndex(zkVFS VFS) (*BacklinkIndex, error) {
+	idx := NewBacklinkIndex()
+	err := walkVFS(zkVFS, "/", func(notePath string) error {
+		switch {
+		case strings.HasSuffix(notePath, ".html"):
+			return idx.indexHTML(zkVFS, notePath)
+		case strings.HasSuffix(notePath, ".md"):
+			return idx.indexMarkdown(zkVFS, notePath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+func (idx *BacklinkIndex) indexHTML(zkVFS VFS, notePath string) error {
+	content, err := zkVFS.ReadFile(notePath)
+	if err != nil {
+		return err
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	doc.Find("a").Each(func(_ int, elem *goquery.Selection) {
+		href, exists := elem.Attr("href")
+		if !exists || href == "" {
+			return
+		}
+		target := resolveNoteLink(zkVFS, notePath, href)
+		idx.add(target, notePath)
+	})
+	return nil
+}
+
+func (idx *BacklinkIndex) indexMarkdown(zkVFS VFS, notePath string) error {
+	content, err := zkVFS.ReadFile(notePath)
+	if err != nil {
+		return err
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for _, groups := range mdLinkPattern.FindAllStringSubmatch(string(content), -1) {
+		idx.add(resolveNoteLink(zkVFS, notePath, groups[2]), notePath)
+	}
+	for _, groups := range wikiLinkPattern.FindAllStringSubmatch(string(content), -1) {
+		idx.add(resolveNoteLink(zkVFS, notePath, groups[1]), notePath)
+	}
+	return nil
+}
+
+// resolveNoteLink resolves an href found inside notePath to the VFS-rooted
+// path of the note it actually targets, leaving absolute links (http://,
+// mailto:, etc.) untouched. Extensionless hrefs ("b") and directory-style
+// hrefs ("dir") are canonicalized to the .html file they refer to, so they
+// land under the same backlinks key that zk mv looks notes up by.
+func resolveNoteLink(zkVFS VFS, notePath string, href string) string {
+	if strings.Contains(href, "://") || strings.HasPrefix(href, "mailto:") {
+		return href
+	}
+	target := href
+	if !strings.HasPrefix(target, "/") {
+		target = path.Join(path.Dir(notePath), href)
+	}
+	return canonicalizeNoteTarget(zkVFS, target)
+}
+
+// canonicalizeNoteTarget maps an extensionless or directory-style target to
+// the .html file it resolves to, mirroring the hrefMatchesPageWithoutExtension
+// and hrefMatchesDirectoryIndex cases rewriteReferencesInFile rewrites.
+func canonicalizeNoteTarget(zkVFS VFS, target string) string {
+	if path.Ext(target) != "" {
+		return target
+	}
+	if _, err := zkVFS.Stat(target + ".html"); err == nil {
+		return target + ".html"
+	}
+	if _, err := zkVFS.Stat(path.Join(target, "index.html")); err == nil {
+		return path.Join(target, "index.html")
+	}
+	return target
+}
+
+// BacklinksJSONHandler serves GET /.zk/backlinks?path=foo/bar.html as JSON.
+func BacklinksJSONHandler(idx *BacklinkIndex) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		notePath := r.URL.Query().Get("path")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string][]string{
+			"backlinks": idx.Backlinks(notePath),
+		})
+	})
+}
+
+// BacklinksFragmentHandler serves GET /.zk/backlinks/foo/bar.html as an
+// HTML fragment suitable for embedding via <iframe> or client-side fetch.
+func BacklinksFragmentHandler(idx *BacklinkIndex, prefix string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		notePath := strings.TrimPrefix(r.URL.Path, prefix)
+		backlinks := idx.Backlinks(notePath)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, "<ul class=\"zk-backlinks\">")
+		for _, source := range backlinks {
+			escaped := html.EscapeString(source)
+			fmt.Fprintf(w, "<li><a href=\"%s\">%s</a></li>", escaped, escaped)
+		}
+		fmt.Fprint(w, "</ul>")
+	})
+}
+
+// WatchForRebuild watches zkRootDirectory for changes and rebuilds idx from
+// zkVFS lazily: instead of rebuilding synchronously on every event, it
+// refreshes once on the next request by swapping backlinks under the lock.
+func WatchForRebuild(idx *BacklinkIndex, zkVFS VFS, zkRootDirectory string) (*fsnotify.Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watchRecursive(watcher, zkRootDirectory); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	go func() {
+		for event := range watcher.Events {
+			trackNewDirectories(watcher, event)
+			rebuilt, err := BuildBacklinkIndex(zkVFS)
+			if err != nil {
+				continue
+			}
+			idx.mu.Lock()
+			idx.backlinks = rebuilt.backlinks
+			idx.mu.Unlock()
+		}
+	}()
+	return watcher, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFile writes content to relPath under dir, creating parent
+// directories as needed.
+func writeFile(t *testing.T, dir string, relPath string, content string) {
+	t.Helper()
+	fullPath := filepath.Join(dir, relPath)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBacklinksCanonicalizesExtensionlessHref(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.html", `<html><body><a href="b">b</a></body></html>`)
+	writeFile(t, dir, "b.html", `<html><body>b</body></html>`)
+
+	idx, err := BuildBacklinkIndex(&LocalFS{Root: dir})
+	if err != nil {
+		t.Fatalf("BuildBacklinkIndex: %v", err)
+	}
+
+	sources := idx.Backlinks("/b.html")
+	if len(sources) != 1 || sources[0] != "/a.html" {
+		t.Fatalf("expected [/a.html], got %v", sources)
+	}
+}
+
+func TestBacklinksCanonicalizesDirectoryIndexHref(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.html", `<html><body><a href="notes">notes</a></body></html>`)
+	writeFile(t, dir, "notes/index.html", `<html><body>notes</body></html>`)
+
+	idx, err := BuildBacklinkIndex(&LocalFS{Root: dir})
+	if err != nil {
+		t.Fatalf("BuildBacklinkIndex: %v", err)
+	}
+
+	sources := idx.Backlinks("/notes/index.html")
+	if len(sources) != 1 || sources[0] != "/a.html" {
+		t.Fatalf("expected [/a.html], got %v", sources)
+	}
+}
+
+func TestBacklinksFragmentHandlerEscapesSourcePath(t *testing.T) {
+	idx := NewBacklinkIndex()
+	idx.add("/b.html", `/"><script>alert(1)</script>.html`)
+
+	handler := BacklinksFragmentHandler(idx, "/.zk/backlinks")
+	req := httptest.NewRequest(http.MethodGet, "/.zk/backlinks/b.html", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); strings.Contains(body, "<script>") {
+		t.Fatalf("expected source to be HTML-escaped, got: %s", body)
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+)
+
+// RootedHandler rejects any request whose path, once resolved
+// component-by-component against root, would escape it (e.g. via a
+// symlink inside the zettelkasten pointing outside zkRootDirectory).
+// Requests for genuinely missing paths are passed through so next can
+// produce its usual 404.
+func RootedHandler(root *Root, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := root.Resolve(r.URL.Path); err != nil && errors.Is(err, ErrPathEscapesRoot) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
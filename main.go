@@ -4,15 +4,11 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	"io/fs"
-	"io/ioutil"
 	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
-
-	"github.com/PuerkitoBio/goquery"
 )
 
 const (
@@ -33,6 +29,8 @@ func PrintHelpText() {
 	fmt.Println("\tzk version\tGet the current ZK CLI version")
 	fmt.Println("\tzk serve\tServe a ZK in the current working directory")
 	fmt.Println("\tzk mv frompath topath\tMove the item(s) at frompath to topath, updating references to them")
+	fmt.Println("\tzk backlinks path\tPrint the notes that link to path")
+	fmt.Println("\tzk search query\tPrint notes matching query, ranked by relevance")
 }
 
 func IsRootDir(path string) bool {
@@ -65,6 +63,25 @@ func ZkRoot(desiredPath string) (string, error) {
 	return desiredPath, nil
 }
 
+// ZkRootVFS resolves desiredPath to a servable VFS, either a LocalFS rooted
+// at the nearest directory containing a .zk-root file, or a ZipFS rooted at
+// the .zk-root directory inside a zip archive, when desiredPath ends in
+// .zip or a sibling .zk-root.zip archive exists alongside it.
+func ZkRootVFS(desiredPath string) (VFS, string, error) {
+	if archivePath := archiveCandidatePath(desiredPath); archivePath != "" {
+		zipFS, err := openZkRootArchive(archivePath)
+		if err != nil {
+			return nil, "", err
+		}
+		return zipFS, archivePath, nil
+	}
+	rootDir, err := ZkRoot(desiredPath)
+	if err != nil {
+		return nil, "", err
+	}
+	return &LocalFS{Root: rootDir}, rootDir, nil
+}
+
 func main() {
 	// The port on localhost to which the server will bind
 	// on localhost, when the "serve" subcommand or its alias are executed
@@ -90,7 +107,6 @@ func main() {
 		// to localhost at a given port. It enables the project
 		// to be read / consumed from the browser
 		var serverDirectory string
-		var zkRootDirectory string
 		serveFlagSet.Parse(os.Args[2:])
 		workingDir, err := os.Getwd()
 		if err != nil {
@@ -98,14 +114,66 @@ func main() {
 			os.Exit(1)
 		}
 		serverDirectory = filepath.Join(workingDir, serverDesiredDirectory)
-		zkRootDirectory, err = ZkRoot(serverDirectory)
+		zkVFS, zkRootDirectory, err := ZkRootVFS(serverDirectory)
 		if err != nil {
 			fmt.Printf("Error finding ZK root: %s\n", err.Error())
 			os.Exit(1)
 		}
 
-		fs := http.FileServer(http.Dir(zkRootDirectory))
-		http.Handle("/", fs)
+		var httpFS http.FileSystem
+		var servedHandler http.Handler
+		if localFS, ok := zkVFS.(*LocalFS); ok {
+			httpFS = http.Dir(localFS.Root)
+			fs := http.FileServer(httpFS)
+			localRoot, err := NewRoot(localFS.Root)
+			if err != nil {
+				fmt.Printf("Error resolving ZK root: %s\n", err.Error())
+				os.Exit(1)
+			}
+			servedHandler = NegotiatingFileHandler(zkVFS, localRoot, fs)
+			servedHandler = RootedHandler(localRoot, servedHandler)
+		} else {
+			httpFS = zkVFS.(*ZipFS).FileSystem
+			fs := http.FileServer(httpFS)
+			servedHandler = NegotiatingFileHandler(zkVFS, nil, fs)
+		}
+		http.Handle("/", servedHandler)
+
+		backlinkIndex, err := BuildBacklinkIndex(zkVFS)
+		if err != nil {
+			fmt.Printf("Error building backlinks index: %s\n", err.Error())
+			os.Exit(1)
+		}
+		if watcher, err := WatchForRebuild(backlinkIndex, zkVFS, zkRootDirectory); err == nil {
+			defer watcher.Close()
+		}
+		http.Handle("/.zk/backlinks", BacklinksJSONHandler(backlinkIndex))
+		http.Handle("/.zk/backlinks/", BacklinksFragmentHandler(backlinkIndex, "/.zk/backlinks"))
+
+		var searchIndex *SearchIndex
+		if localFS, ok := zkVFS.(*LocalFS); ok {
+			searchIndex, err = LoadSearchIndex(localFS.Root)
+			if err != nil {
+				fmt.Printf("Error loading search index: %s\n", err.Error())
+				os.Exit(1)
+			}
+		} else {
+			searchIndex = NewSearchIndex()
+		}
+		if err := BuildSearchIndex(searchIndex, zkVFS); err != nil {
+			fmt.Printf("Error building search index: %s\n", err.Error())
+			os.Exit(1)
+		}
+		if localFS, ok := zkVFS.(*LocalFS); ok {
+			if err := searchIndex.Save(localFS.Root); err != nil {
+				fmt.Printf("Error saving search index: %s\n", err.Error())
+				os.Exit(1)
+			}
+			if watcher, err := WatchSearchIndex(searchIndex, zkVFS, localFS.Root); err == nil {
+				defer watcher.Close()
+			}
+		}
+		http.Handle("/.zk/search", SearchHandler(searchIndex))
 
 		fmt.Printf("Listening on http://localhost:%d\n", serverPort)
 		err = http.ListenAndServe(fmt.Sprintf(":%d", serverPort), nil)
@@ -113,6 +181,64 @@ func main() {
 			fmt.Printf("Error starting HTTP server: %s\n", err.Error())
 			os.Exit(1)
 		}
+	case "backlinks":
+		if len(os.Args) < 3 {
+			fmt.Println("zk backlinks usage:")
+			fmt.Println("\tzk backlinks path\tPrint the notes that link to path")
+			os.Exit(1)
+		}
+		workingDir, err := os.Getwd()
+		if err != nil {
+			fmt.Printf("Error getting current working directory: %s\n", err.Error())
+			os.Exit(1)
+		}
+		zkRootDirectory, err := ZkRoot(workingDir)
+		if err != nil {
+			fmt.Printf("Error finding ZK root: %s\n", err.Error())
+			os.Exit(1)
+		}
+		backlinkIndex, err := BuildBacklinkIndex(&LocalFS{Root: zkRootDirectory})
+		if err != nil {
+			fmt.Printf("Error building backlinks index: %s\n", err.Error())
+			os.Exit(1)
+		}
+		notePath := os.Args[2]
+		for _, source := range backlinkIndex.Backlinks(notePath) {
+			fmt.Println(source)
+		}
+	case "search":
+		if len(os.Args) < 3 {
+			fmt.Println("zk search usage:")
+			fmt.Println("\tzk search query\tPrint notes matching query, ranked by relevance")
+			os.Exit(1)
+		}
+		workingDir, err := os.Getwd()
+		if err != nil {
+			fmt.Printf("Error getting current working directory: %s\n", err.Error())
+			os.Exit(1)
+		}
+		zkRootDirectory, err := ZkRoot(workingDir)
+		if err != nil {
+			fmt.Printf("Error finding ZK root: %s\n", err.Error())
+			os.Exit(1)
+		}
+		searchIndex, err := LoadSearchIndex(zkRootDirectory)
+		if err != nil {
+			fmt.Printf("Error loading search index: %s\n", err.Error())
+			os.Exit(1)
+		}
+		if err := BuildSearchIndex(searchIndex, &LocalFS{Root: zkRootDirectory}); err != nil {
+			fmt.Printf("Error building search index: %s\n", err.Error())
+			os.Exit(1)
+		}
+		if err := searchIndex.Save(zkRootDirectory); err != nil {
+			fmt.Printf("Error saving search index: %s\n", err.Error())
+			os.Exit(1)
+		}
+		query := strings.Join(os.Args[2:], " ")
+		for _, hit := range searchIndex.Search(query, 20) {
+			fmt.Printf("%.3f\t%s\n", hit.Score, hit.Path)
+		}
 	case "mv", "move":
 		if len(os.Args) < 4 {
 			fmt.Println("zk mv usage:")
@@ -131,50 +257,44 @@ func main() {
 			fmt.Printf("Error finding ZK root: %s\n", err.Error())
 			os.Exit(1)
 		}
-		absFromPath := filepath.Join(workingDir, fromPath)
-		absToPath := filepath.Join(workingDir, toPath)
+		zkRoot, err := NewRoot(zkRootDirectory)
+		if err != nil {
+			fmt.Printf("Error resolving ZK root: %s\n", err.Error())
+			os.Exit(1)
+		}
+		workingDirRelative := workingDir[len(zkRootDirectory):]
+		absFromPath, err := zkRoot.Resolve(filepath.Join(workingDirRelative, fromPath))
+		if err != nil {
+			if errors.Is(err, ErrPathEscapesRoot) {
+				fmt.Printf("%s escapes the ZK root\n", fromPath)
+			} else {
+				fmt.Printf("File %s does not exist\n", filepath.Join(workingDir, fromPath))
+			}
+			os.Exit(1)
+		}
+		absToPath, err := zkRoot.ResolveDestination(filepath.Join(workingDirRelative, toPath))
+		if err != nil {
+			fmt.Printf("%s escapes the ZK root\n", toPath)
+			os.Exit(1)
+		}
 		zkRelativeFromPath := absFromPath[len(zkRootDirectory):]
 		zkRelativeToPath := absToPath[len(zkRootDirectory):]
 		zkRelativeToPath = strings.TrimSuffix(zkRelativeToPath, "/index.html")
-		_, err = os.Stat(absFromPath)
-		if errors.Is(err, os.ErrNotExist) {
-			fmt.Printf("File %s does not exist\n", absFromPath)
-			os.Exit(1)
-		}
-		filepath.WalkDir(zkRootDirectory, func(path string, dirEntry fs.DirEntry, err error) error {
-			// If this is an HTML file, we can work with it
-			if strings.HasSuffix(dirEntry.Name(), ".html") {
-				fileContent, err := os.Open(path) // the file is inside the local directory
-				if err != nil {
-					return err
-				}
-				defer fileContent.Close()
-				html, err := goquery.NewDocumentFromReader(fileContent)
-				if err != nil {
-					return err
-				}
-				foundReference := false
-				html.Find("a").Each(func(index int, elem *goquery.Selection) {
-					href, exists := elem.Attr("href")
-					hrefMatchesDirectoryIndex := (filepath.Base(zkRelativeFromPath) == "index.html" && href == filepath.Dir(zkRelativeFromPath))
-					hrefMatchesPageWithoutExtension := strings.HasSuffix(zkRelativeFromPath, ".html") && href == zkRelativeFromPath[:len(zkRelativeFromPath)-5]
-					if exists && (href == zkRelativeFromPath || hrefMatchesDirectoryIndex || hrefMatchesPageWithoutExtension) {
-						foundReference = true
-						elem.SetAttr("href", zkRelativeToPath)
-					}
-				})
-				if foundReference {
-					// Only edit a file if it references our moving file
-					newHtmlString, err := html.Html()
-					if err != nil {
-						return err
-					}
-					newHtmlBytes := []byte(newHtmlString)
-					ioutil.WriteFile(path, newHtmlBytes, 0777)
-				}
+		// Reuse the backlinks graph instead of walking every file in the
+		// zettelkasten: only notes that actually reference fromPath need rewriting.
+		zkVFS := &LocalFS{Root: zkRootDirectory}
+		backlinkIndex, err := BuildBacklinkIndex(zkVFS)
+		if err != nil {
+			fmt.Printf("Error building backlinks index: %s\n", err.Error())
+			os.Exit(1)
+		}
+		for _, source := range backlinkIndex.Backlinks(zkRelativeFromPath) {
+			sourcePath := filepath.Join(zkRootDirectory, source)
+			if err := rewriteReferencesInFile(zkVFS, source, sourcePath, zkRelativeFromPath, zkRelativeToPath); err != nil {
+				fmt.Printf("Error rewriting references in %s: %s\n", sourcePath, err.Error())
+				os.Exit(1)
 			}
-			return nil
-		})
+		}
 		err = os.Rename(absFromPath, absToPath)
 		if err != nil {
 			fmt.Printf("Error moving item in file tree: %s\n", err.Error())
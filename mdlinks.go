@@ -0,0 +1,39 @@
+package main
+
+import "regexp"
+
+// mdLinkPattern matches Markdown inline links: [text](link)
+var mdLinkPattern = regexp.MustCompile(`\[([^\]]*)\]\(([^)]+)\)`)
+
+// wikiLinkPattern matches wiki-style links: [[link]]
+var wikiLinkPattern = regexp.MustCompile(`\[\[([^\]]+)\]\]`)
+
+// rewriteMarkdownLinks rewrites any `[text](link)` or `[[link]]` reference
+// that resolves (via resolveNoteLink, the same way the backlinks index
+// resolved it) to from, found in notePath's content, so that it instead
+// points at to. It reports whether any reference was rewritten.
+func rewriteMarkdownLinks(zkVFS VFS, notePath string, content string, from string, to string) (string, bool) {
+	rewritten := false
+
+	content = mdLinkPattern.ReplaceAllStringFunc(content, func(match string) string {
+		groups := mdLinkPattern.FindStringSubmatch(match)
+		text, link := groups[1], groups[2]
+		if resolveNoteLink(zkVFS, notePath, link) != from {
+			return match
+		}
+		rewritten = true
+		return "[" + text + "](" + to + ")"
+	})
+
+	content = wikiLinkPattern.ReplaceAllStringFunc(content, func(match string) string {
+		groups := wikiLinkPattern.FindStringSubmatch(match)
+		link := groups[1]
+		if resolveNoteLink(zkVFS, notePath, link) != from {
+			return match
+		}
+		rewritten = true
+		return "[[" + to + "]]"
+	})
+
+	return content, rewritten
+}
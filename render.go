@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+const (
+	ZK_TEMPLATE_DIRECTORY = ".zk/templates"
+	PAGE_TEMPLATE_NAME    = "page.html"
+)
+
+// defaultPageTemplate is used when the zettelkasten doesn't ship its own
+// .zk/templates/page.html.
+const defaultPageTemplate = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body>{{.Content}}</body>
+</html>
+`
+
+// pageData is passed to the page template when rendering a Markdown note.
+type pageData struct {
+	Title   string
+	Content template.HTML
+}
+
+// loadPageTemplate reads .zk/templates/page.html through zkVFS, falling
+// back to defaultPageTemplate when the zettelkasten doesn't define one. It
+// goes through zkVFS rather than the real filesystem so a custom template
+// shipped inside a .zip-backed zettelkasten is reachable too.
+func loadPageTemplate(zkVFS VFS) (*template.Template, error) {
+	templatePath := path.Join("/", ZK_TEMPLATE_DIRECTORY, PAGE_TEMPLATE_NAME)
+	raw, err := zkVFS.ReadFile(templatePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return template.New(PAGE_TEMPLATE_NAME).Parse(defaultPageTemplate)
+		}
+		return nil, err
+	}
+	return template.New(PAGE_TEMPLATE_NAME).Parse(string(raw))
+}
+
+// renderMarkdown converts Markdown source into an HTML page using the
+// zettelkasten's page template.
+func renderMarkdown(zkVFS VFS, source []byte) ([]byte, error) {
+	tmpl, err := loadPageTemplate(zkVFS)
+	if err != nil {
+		return nil, err
+	}
+	body := blackfriday.Run(source)
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, pageData{Title: "", Content: template.HTML(body)}); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// mdCandidatePaths returns the ordered list of VFS-relative paths to try
+// for an incoming request path: the path itself, then path+".md", then
+// path+".html", then an index.md/index.html inside it if it has no
+// extension.
+func mdCandidatePaths(requestPath string) []string {
+	candidates := []string{}
+	if filepath.Ext(requestPath) != "" {
+		candidates = append(candidates, requestPath)
+		return candidates
+	}
+	trimmed := strings.TrimSuffix(requestPath, "/")
+	candidates = append(candidates,
+		trimmed+".md",
+		trimmed+".html",
+		filepath.Join(trimmed, "index.md"),
+		filepath.Join(trimmed, "index.html"),
+	)
+	return candidates
+}
+
+// NegotiatingFileHandler serves Markdown notes as rendered HTML and falls
+// back to the underlying VFS/http.FileServer for everything else. If root
+// is non-nil, every candidate path is resolved through it first so that
+// symlinks reachable via mdCandidatePaths (e.g. "page" -> "page.md") get
+// the same escape checks as the literal request path, instead of being
+// read straight off disk.
+func NegotiatingFileHandler(zkVFS VFS, root *Root, fallback http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".md") {
+			if rootRejects(w, root, r.URL.Path) {
+				return
+			}
+			serveMarkdown(w, r, zkVFS, r.URL.Path)
+			return
+		}
+		if filepath.Ext(r.URL.Path) == "" {
+			for _, candidate := range mdCandidatePaths(r.URL.Path) {
+				if _, err := zkVFS.Stat(candidate); err != nil {
+					continue
+				}
+				if rootRejects(w, root, candidate) {
+					return
+				}
+				if strings.HasSuffix(candidate, ".md") {
+					serveMarkdown(w, r, zkVFS, candidate)
+				} else {
+					serveVerbatim(w, r, zkVFS, candidate)
+				}
+				return
+			}
+		}
+		fallback.ServeHTTP(w, r)
+	})
+}
+
+// rootRejects resolves candidate through root, writing a 403 and returning
+// true if it would escape (e.g. via a symlink). A nil root (a VFS with no
+// on-disk symlinks to worry about, e.g. a zip archive) never rejects.
+func rootRejects(w http.ResponseWriter, root *Root, candidate string) bool {
+	if root == nil {
+		return false
+	}
+	if _, err := root.Resolve(candidate); err != nil && errors.Is(err, ErrPathEscapesRoot) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return true
+	}
+	return false
+}
+
+// serveMarkdown reads mdPath from zkVFS, renders it, and writes the result
+// as text/html.
+func serveMarkdown(w http.ResponseWriter, r *http.Request, zkVFS VFS, mdPath string) {
+	source, err := zkVFS.ReadFile(mdPath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	rendered, err := renderMarkdown(zkVFS, source)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(rendered)
+}
+
+// serveVerbatim reads htmlPath from zkVFS and writes it unmodified, for the
+// .html/index.html candidates mdCandidatePaths tries that don't go through
+// the Markdown renderer.
+func serveVerbatim(w http.ResponseWriter, r *http.Request, zkVFS VFS, htmlPath string) {
+	content, err := zkVFS.ReadFile(htmlPath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(content)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNegotiatingFileHandlerServesExtensionlessHTML(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "about.html", `<html><body>about page</body></html>`)
+
+	zkVFS := &LocalFS{Root: dir}
+	fallback := http.NotFoundHandler()
+	handler := NegotiatingFileHandler(zkVFS, nil, fallback)
+
+	req := httptest.NewRequest(http.MethodGet, "/about", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if got := rec.Body.String(); got != `<html><body>about page</body></html>` {
+		t.Fatalf("unexpected body: %s", got)
+	}
+}
+
+func TestRenderMarkdownUsesTemplateFromZip(t *testing.T) {
+	zipPath := writeTestZip(t, map[string]string{
+		".zk-root":                "",
+		".zk/templates/page.html": `<!DOCTYPE html><html><body class="custom">{{.Content}}</body></html>`,
+		"note.md":                 "# hello",
+	})
+	zkVFS, err := openZkRootArchive(zipPath)
+	if err != nil {
+		t.Fatalf("openZkRootArchive: %v", err)
+	}
+
+	rendered, err := renderMarkdown(zkVFS, []byte("# hello"))
+	if err != nil {
+		t.Fatalf("renderMarkdown: %v", err)
+	}
+	if !strings.Contains(string(rendered), `class="custom"`) {
+		t.Fatalf("expected zip-backed template to be used, got: %s", rendered)
+	}
+}
+
+func TestNegotiatingFileHandlerPrefersMarkdownOverHTML(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "about.md", `# about`)
+	writeFile(t, dir, "about.html", `<html><body>stale</body></html>`)
+
+	zkVFS := &LocalFS{Root: dir}
+	handler := NegotiatingFileHandler(zkVFS, nil, http.NotFoundHandler())
+
+	req := httptest.NewRequest(http.MethodGet, "/about", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if got := rec.Body.String(); !strings.Contains(got, "<h1>about</h1>") {
+		t.Fatalf("expected rendered markdown, got: %s", got)
+	}
+}
+
+// TestRootedNegotiatingFileHandlerBlocksSymlinkEscape wires RootedHandler and
+// NegotiatingFileHandler together the way main.go does for an on-disk
+// zettelkasten, and confirms a symlink reachable only via an extensionless
+// candidate path (page -> page.md) is rejected rather than served.
+func TestRootedNegotiatingFileHandlerBlocksSymlinkEscape(t *testing.T) {
+	outsideDir := t.TempDir()
+	writeFile(t, outsideDir, "secret.md", `# secret`)
+
+	dir := t.TempDir()
+	if err := os.Symlink(filepath.Join(outsideDir, "secret.md"), filepath.Join(dir, "page.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := NewRoot(dir)
+	if err != nil {
+		t.Fatalf("NewRoot: %v", err)
+	}
+	zkVFS := &LocalFS{Root: dir}
+	handler := RootedHandler(root, NegotiatingFileHandler(zkVFS, root, http.NotFoundHandler()))
+
+	req := httptest.NewRequest(http.MethodGet, "/page", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a symlink escaping the root, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
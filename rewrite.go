@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// rewriteReferencesInFile rewrites any reference to zkRelativeFromPath found
+// in the HTML or Markdown file at diskPath so that it points at
+// zkRelativeToPath instead. notePath is diskPath's VFS-rooted path (as
+// BuildBacklinkIndex indexed it), used to resolve relative/extensionless
+// hrefs through resolveNoteLink exactly as the backlinks index did, so a
+// file it reports as a referrer is actually rewritten here too. It's the
+// per-file body of zk mv's reference-rewriting pass.
+func rewriteReferencesInFile(zkVFS VFS, notePath string, diskPath string, zkRelativeFromPath string, zkRelativeToPath string) error {
+	switch {
+	case strings.HasSuffix(diskPath, ".md"):
+		fileContent, err := os.ReadFile(diskPath)
+		if err != nil {
+			return err
+		}
+		newContent, rewritten := rewriteMarkdownLinks(zkVFS, notePath, string(fileContent), zkRelativeFromPath, zkRelativeToPath)
+		if rewritten {
+			return os.WriteFile(diskPath, []byte(newContent), 0777)
+		}
+	case strings.HasSuffix(diskPath, ".html"):
+		fileContent, err := os.Open(diskPath)
+		if err != nil {
+			return err
+		}
+		defer fileContent.Close()
+		html, err := goquery.NewDocumentFromReader(fileContent)
+		if err != nil {
+			return err
+		}
+		foundReference := false
+		html.Find("a").Each(func(index int, elem *goquery.Selection) {
+			href, exists := elem.Attr("href")
+			if !exists || href == "" {
+				return
+			}
+			if resolveNoteLink(zkVFS, notePath, href) == zkRelativeFromPath {
+				foundReference = true
+				elem.SetAttr("href", zkRelativeToPath)
+			}
+		})
+		if foundReference {
+			newHtmlString, err := html.Html()
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(diskPath, []byte(newHtmlString), 0777)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRewriteReferencesInFileRewritesRelativeHTMLLink(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "notes/a.html", `<html><body><a href="../b.html">b</a></body></html>`)
+	writeFile(t, dir, "b.html", `<html><body>b</body></html>`)
+
+	zkVFS := &LocalFS{Root: dir}
+	diskPath := filepath.Join(dir, "notes/a.html")
+	if err := rewriteReferencesInFile(zkVFS, "/notes/a.html", diskPath, "/b.html", "/c.html"); err != nil {
+		t.Fatalf("rewriteReferencesInFile: %v", err)
+	}
+
+	content, err := os.ReadFile(diskPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(content); !strings.Contains(got, `href="/c.html"`) {
+		t.Fatalf("expected relative link to be rewritten to /c.html, got: %s", got)
+	}
+}
+
+func TestRewriteReferencesInFileRewritesRelativeMarkdownLink(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "notes/a.md", `see [b](../b.md)`)
+	writeFile(t, dir, "b.md", `# b`)
+
+	zkVFS := &LocalFS{Root: dir}
+	diskPath := filepath.Join(dir, "notes/a.md")
+	if err := rewriteReferencesInFile(zkVFS, "/notes/a.md", diskPath, "/b.md", "/c.md"); err != nil {
+		t.Fatalf("rewriteReferencesInFile: %v", err)
+	}
+
+	content, err := os.ReadFile(diskPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(content); !strings.Contains(got, "[b](/c.md)") {
+		t.Fatalf("expected relative link to be rewritten to /c.md, got: %s", got)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ErrPathEscapesRoot is returned by Root.Resolve when a requested path, or
+// a symlink encountered while walking it, would resolve outside the root.
+var ErrPathEscapesRoot = errors.New("path escapes zk root")
+
+// Root is a rooted path resolver, inspired by gitlab-pages' VFS root: it
+// resolves a request path component-by-component against a base directory,
+// Lstat-ing each intermediate component so that no symlink along the way
+// can point outside the base directory.
+type Root struct {
+	base string
+}
+
+// NewRoot returns a Root rooted at base, which must itself be free of
+// symlinks relative to its own resolved form.
+func NewRoot(base string) (*Root, error) {
+	resolvedBase, err := filepath.EvalSymlinks(base)
+	if err != nil {
+		return nil, err
+	}
+	return &Root{base: resolvedBase}, nil
+}
+
+// Resolve walks requestPath component-by-component from the root,
+// rejecting the request if any intermediate symlink resolves outside the
+// root. Every component, including the last, must exist.
+func (r *Root) Resolve(requestPath string) (string, error) {
+	return r.resolve(requestPath, false)
+}
+
+// ResolveDestination is like Resolve but allows the final component to not
+// exist yet, for use with operations (like zk mv) whose destination path
+// hasn't been created.
+func (r *Root) ResolveDestination(requestPath string) (string, error) {
+	return r.resolve(requestPath, true)
+}
+
+func (r *Root) resolve(requestPath string, allowMissingLeaf bool) (string, error) {
+	clean := filepath.Clean(string(filepath.Separator) + requestPath)
+	components := strings.Split(clean, string(filepath.Separator))
+
+	current := r.base
+	consumedComponent := false
+	for i, component := range components {
+		if component == "" || component == "." {
+			continue
+		}
+		consumedComponent = true
+		current = filepath.Join(current, component)
+		isLast := i == len(components)-1
+
+		info, err := os.Lstat(current)
+		if err != nil {
+			if isLast && allowMissingLeaf && os.IsNotExist(err) {
+				break
+			}
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			if err := r.checkWithinRoot(current); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	// When requestPath collapses to the root itself (e.g. "/"), current is
+	// r.base, so it's what must be checked, not its parent directory.
+	checkTarget := filepath.Dir(current)
+	if !consumedComponent {
+		checkTarget = current
+	}
+	if err := r.checkWithinRoot(checkTarget); err != nil {
+		return "", err
+	}
+	return current, nil
+}
+
+// checkWithinRoot verifies that candidate, once symlinks are resolved,
+// still lives inside the root.
+func (r *Root) checkWithinRoot(candidate string) error {
+	resolved, err := filepath.EvalSymlinks(candidate)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Nothing left to resolve; the unresolved path was already
+			// validated component-by-component above.
+			return nil
+		}
+		return err
+	}
+	if runtime.GOOS == "windows" && filepath.VolumeName(resolved) != filepath.VolumeName(r.base) {
+		return ErrPathEscapesRoot
+	}
+	rel, err := filepath.Rel(r.base, resolved)
+	if err != nil {
+		return err
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ErrPathEscapesRoot
+	}
+	return nil
+}
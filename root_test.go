@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRootResolveRootPath(t *testing.T) {
+	dir := t.TempDir()
+	root, err := NewRoot(dir)
+	if err != nil {
+		t.Fatalf("NewRoot: %v", err)
+	}
+	if _, err := root.Resolve("/"); err != nil {
+		t.Fatalf("Resolve(\"/\") should succeed for a plain directory, got: %v", err)
+	}
+}
+
+func TestRootResolveRejectsSymlinkEscape(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "secret.txt")
+	if err := os.WriteFile(secret, []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(secret, filepath.Join(dir, "escape.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := NewRoot(dir)
+	if err != nil {
+		t.Fatalf("NewRoot: %v", err)
+	}
+	if _, err := root.Resolve("/escape.txt"); err != ErrPathEscapesRoot {
+		t.Fatalf("expected ErrPathEscapesRoot, got: %v", err)
+	}
+}
+
+func TestRootResolveAllowsRegularFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "note.html"), []byte("hi"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewRoot(dir)
+	if err != nil {
+		t.Fatalf("NewRoot: %v", err)
+	}
+	resolved, err := root.Resolve("/note.html")
+	if err != nil {
+		t.Fatalf("Resolve(/note.html): %v", err)
+	}
+	if filepath.Base(resolved) != "note.html" {
+		t.Fatalf("unexpected resolved path: %s", resolved)
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	SEARCH_INDEX_PATH = ".zk/index.gob"
+	bm25K1            = 1.2
+	bm25B             = 0.75
+)
+
+var tokenPattern = regexp.MustCompile(`[a-zA-Z0-9]+`)
+
+// posting records where and how often a token occurs in a single document.
+type posting struct {
+	TermFreq  int
+	Positions []int
+}
+
+// document holds everything the index needs about a single note beyond its
+// postings: its raw text (for snippets), length in tokens, and the mtime it
+// was indexed at (to detect when it needs reindexing).
+type document struct {
+	Text   string
+	Length int
+	MTime  time.Time
+}
+
+// SearchIndex is an in-process inverted index with BM25 scoring over the
+// zettelkasten's text content.
+type SearchIndex struct {
+	mu       sync.RWMutex
+	postings map[string]map[string]posting // token -> path -> posting
+	docs     map[string]document           // path -> document
+}
+
+// searchHit is one ranked result from SearchIndex.Search.
+type searchHit struct {
+	Path    string  `json:"path"`
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+}
+
+// persistedIndex is the gob-serializable form of SearchIndex, written to
+// <zkroot>/.zk/index.gob so restarts don't have to reindex unchanged files.
+type persistedIndex struct {
+	Postings map[string]map[string]posting
+	Docs     map[string]document
+}
+
+// NewSearchIndex returns an empty SearchIndex.
+func NewSearchIndex() *SearchIndex {
+	return &SearchIndex{
+		postings: make(map[string]map[string]posting),
+		docs:     make(map[string]document),
+	}
+}
+
+// LoadSearchIndex reads a persisted index from <zkroot>/.zk/index.gob, if
+// one exists. A missing file is not an error: it just means an empty
+// index, to be filled in by BuildSearchIndex.
+func LoadSearchIndex(zkRootDirectory string) (*SearchIndex, error) {
+	idx := NewSearchIndex()
+	raw, err := os.ReadFile(filepath.Join(zkRootDirectory, SEARCH_INDEX_PATH))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return idx, nil
+		}
+		return nil, err
+	}
+	var persisted persistedIndex
+	if err := gob.NewDecoder(bytes.NewReader(raw)).Decode(&persisted); err != nil {
+		return nil, err
+	}
+	idx.postings = persisted.Postings
+	idx.docs = persisted.Docs
+	return idx, nil
+}
+
+// Save persists idx to <zkroot>/.zk/index.gob.
+func (idx *SearchIndex) Save(zkRootDirectory string) error {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(persistedIndex{Postings: idx.postings, Docs: idx.docs}); err != nil {
+		return err
+	}
+	indexDir := filepath.Join(zkRootDirectory, ".zk")
+	if err := os.MkdirAll(indexDir, 0777); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(zkRootDirectory, SEARCH_INDEX_PATH), buf.Bytes(), 0666)
+}
+
+// tokenize lowercases text and splits it into alphanumeric tokens.
+func tokenize(text string) []string {
+	matches := tokenPattern.FindAllString(strings.ToLower(text), -1)
+	return matches
+}
+
+// removeDoc drops every posting belonging to path, ahead of reindexing it.
+func (idx *SearchIndex) removeDoc(path string) {
+	for token, byPath := range idx.postings {
+		delete(byPath, path)
+		if len(byPath) == 0 {
+			delete(idx.postings, token)
+		}
+	}
+	delete(idx.docs, path)
+}
+
+// indexDocument tokenizes text and (re)indexes it under path, stamped with
+// mtime so future runs can skip unchanged files.
+func (idx *SearchIndex) indexDocument(path string, text string, mtime time.Time) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.removeDoc(path)
+
+	tokens := tokenize(text)
+	termPostings := make(map[string]posting)
+	for position, token := range tokens {
+		p := termPostings[token]
+		p.TermFreq++
+		p.Positions = append(p.Positions, position)
+		termPostings[token] = p
+	}
+	for token, p := range termPostings {
+		if idx.postings[token] == nil {
+			idx.postings[token] = make(map[string]posting)
+		}
+		idx.postings[token][path] = p
+	}
+	idx.docs[path] = document{Text: text, Length: len(tokens), MTime: mtime}
+}
+
+// extractText returns the plain-text body of an HTML or Markdown note.
+func extractText(path string, content []byte) (string, error) {
+	if strings.HasSuffix(path, ".html") {
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(content))
+		if err != nil {
+			return "", err
+		}
+		return doc.Text(), nil
+	}
+	return string(content), nil
+}
+
+// BuildSearchIndex indexes every HTML and Markdown note in zkVFS into idx,
+// skipping files whose mtime hasn't changed since they were last indexed,
+// and prunes any previously indexed note that no longer exists.
+func BuildSearchIndex(idx *SearchIndex, zkVFS VFS) error {
+	seen := make(map[string]bool)
+	if err := walkVFS(zkVFS, "/", func(notePath string) error {
+		if !strings.HasSuffix(notePath, ".html") && !strings.HasSuffix(notePath, ".md") {
+			return nil
+		}
+		seen[notePath] = true
+		info, err := zkVFS.Stat(notePath)
+		if err != nil {
+			return err
+		}
+		idx.mu.RLock()
+		existing, indexed := idx.docs[notePath]
+		idx.mu.RUnlock()
+		if indexed && !info.ModTime().After(existing.MTime) {
+			return nil
+		}
+		content, err := zkVFS.ReadFile(notePath)
+		if err != nil {
+			return err
+		}
+		text, err := extractText(notePath, content)
+		if err != nil {
+			return err
+		}
+		idx.indexDocument(notePath, text, info.ModTime())
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	idx.mu.Lock()
+	var stale []string
+	for notePath := range idx.docs {
+		if !seen[notePath] {
+			stale = append(stale, notePath)
+		}
+	}
+	for _, notePath := range stale {
+		idx.removeDoc(notePath)
+	}
+	idx.mu.Unlock()
+	return nil
+}
+
+// averageDocLength returns the mean token count across all indexed docs.
+func (idx *SearchIndex) averageDocLength() float64 {
+	if len(idx.docs) == 0 {
+		return 0
+	}
+	total := 0
+	for _, doc := range idx.docs {
+		total += doc.Length
+	}
+	return float64(total) / float64(len(idx.docs))
+}
+
+// Search ranks every indexed document against query using BM25 and returns
+// the top limit hits.
+func (idx *SearchIndex) Search(query string, limit int) []searchHit {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	terms := tokenize(query)
+	n := float64(len(idx.docs))
+	avgDocLength := idx.averageDocLength()
+
+	scores := make(map[string]float64)
+	bestPosition := make(map[string]int)
+	for _, term := range terms {
+		byPath, ok := idx.postings[term]
+		if !ok {
+			continue
+		}
+		df := float64(len(byPath))
+		idf := math.Log((n-df+0.5)/(df+0.5) + 1)
+		for path, p := range byPath {
+			doc := idx.docs[path]
+			tf := float64(p.TermFreq)
+			denom := tf + bm25K1*(1-bm25B+bm25B*float64(doc.Length)/avgDocLength)
+			scores[path] += idf * (tf * (bm25K1 + 1)) / denom
+			if _, seen := bestPosition[path]; !seen && len(p.Positions) > 0 {
+				bestPosition[path] = p.Positions[0]
+			}
+		}
+	}
+
+	hits := make([]searchHit, 0, len(scores))
+	for path, score := range scores {
+		hits = append(hits, searchHit{
+			Path:    path,
+			Score:   score,
+			Snippet: snippet(idx.docs[path].Text, bestPosition[path]),
+		})
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// snippet returns a ~80-char window of text centered on the tokenPosition'th
+// token, with the matching word wrapped in <mark>.
+func snippet(text string, tokenPosition int) string {
+	tokens := tokenPattern.FindAllStringIndex(text, -1)
+	if len(tokens) == 0 {
+		return ""
+	}
+	if tokenPosition >= len(tokens) {
+		tokenPosition = len(tokens) - 1
+	}
+	matchStart, matchEnd := tokens[tokenPosition][0], tokens[tokenPosition][1]
+
+	windowStart := matchStart - 40
+	if windowStart < 0 {
+		windowStart = 0
+	}
+	windowEnd := matchEnd + 40
+	if windowEnd > len(text) {
+		windowEnd = len(text)
+	}
+
+	return text[windowStart:matchStart] + "<mark>" + text[matchStart:matchEnd] + "</mark>" + text[matchEnd:windowEnd]
+}
+
+// WatchSearchIndex watches zkRootDirectory for changes and reindexes
+// touched files into idx, persisting the result, whenever something changes.
+func WatchSearchIndex(idx *SearchIndex, zkVFS VFS, zkRootDirectory string) (*fsnotify.Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watchRecursive(watcher, zkRootDirectory); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	go func() {
+		for event := range watcher.Events {
+			trackNewDirectories(watcher, event)
+			if err := BuildSearchIndex(idx, zkVFS); err != nil {
+				continue
+			}
+			idx.Save(zkRootDirectory)
+		}
+	}()
+	return watcher, nil
+}
+
+// SearchHandler serves GET /.zk/search?q=...&limit=20 as JSON.
+func SearchHandler(idx *SearchIndex) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("q")
+		limit := 20
+		if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+			limit = l
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string][]searchHit{
+			"hits": idx.Search(query, limit),
+		})
+	})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSearchRanksMoreRelevantDocumentFirst(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "zettelkasten.html", `<html><body>zettelkasten zettelkasten zettelkasten notes</body></html>`)
+	writeFile(t, dir, "other.html", `<html><body>a note about gardening</body></html>`)
+
+	idx := NewSearchIndex()
+	if err := BuildSearchIndex(idx, &LocalFS{Root: dir}); err != nil {
+		t.Fatalf("BuildSearchIndex: %v", err)
+	}
+
+	hits := idx.Search("zettelkasten", 10)
+	if len(hits) == 0 {
+		t.Fatal("expected at least one hit")
+	}
+	if hits[0].Path != "/zettelkasten.html" {
+		t.Fatalf("expected /zettelkasten.html to rank first, got %s", hits[0].Path)
+	}
+	if hits[0].Score <= 0 {
+		t.Fatalf("expected a positive BM25 score, got %f", hits[0].Score)
+	}
+}
+
+func TestSearchReindexesChangedFilesOnly(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.html", `<html><body>hello</body></html>`)
+
+	idx := NewSearchIndex()
+	if err := BuildSearchIndex(idx, &LocalFS{Root: dir}); err != nil {
+		t.Fatalf("BuildSearchIndex: %v", err)
+	}
+	if len(idx.Search("hello", 10)) != 1 {
+		t.Fatal("expected a hit for 'hello' after first build")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	writeFile(t, dir, "a.html", `<html><body>goodbye</body></html>`)
+	if err := BuildSearchIndex(idx, &LocalFS{Root: dir}); err != nil {
+		t.Fatalf("BuildSearchIndex (reindex): %v", err)
+	}
+	if len(idx.Search("hello", 10)) != 0 {
+		t.Fatal("expected stale 'hello' postings to be gone after reindexing")
+	}
+	if len(idx.Search("goodbye", 10)) != 1 {
+		t.Fatal("expected a hit for 'goodbye' after reindexing")
+	}
+}
+
+func TestSearchPrunesDeletedFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.html", `<html><body>hello</body></html>`)
+
+	idx := NewSearchIndex()
+	if err := BuildSearchIndex(idx, &LocalFS{Root: dir}); err != nil {
+		t.Fatalf("BuildSearchIndex: %v", err)
+	}
+	if len(idx.Search("hello", 10)) != 1 {
+		t.Fatal("expected a hit for 'hello' after first build")
+	}
+
+	if err := os.Remove(filepath.Join(dir, "a.html")); err != nil {
+		t.Fatal(err)
+	}
+	if err := BuildSearchIndex(idx, &LocalFS{Root: dir}); err != nil {
+		t.Fatalf("BuildSearchIndex (after delete): %v", err)
+	}
+	if len(idx.Search("hello", 10)) != 0 {
+		t.Fatal("expected the deleted file's postings to be pruned")
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"archive/zip"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const ZK_ROOT_ARCHIVE_FILENAME = ".zk-root.zip"
+
+// VFS is the minimal filesystem surface zk needs in order to serve a
+// zettelkasten, whether it lives on disk or inside a zip archive.
+type VFS interface {
+	Open(name string) (http.File, error)
+	Stat(name string) (os.FileInfo, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	ReadFile(name string) ([]byte, error)
+}
+
+// LocalFS is a VFS backed by a directory on disk, rooted at Root.
+type LocalFS struct {
+	Root string
+}
+
+func (l *LocalFS) Open(name string) (http.File, error) {
+	return http.Dir(l.Root).Open(name)
+}
+
+func (l *LocalFS) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(filepath.Join(l.Root, name))
+}
+
+func (l *LocalFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(filepath.Join(l.Root, name))
+}
+
+func (l *LocalFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(l.Root, name))
+}
+
+// ZipFS is a VFS backed by a zip archive, rooted at some directory inside it.
+type ZipFS struct {
+	Reader *zip.Reader
+	// Root is the directory inside the archive (e.g. ".zk-root") that
+	// should be treated as the virtual filesystem root.
+	Root string
+	http.FileSystem
+}
+
+// NewZipFS opens an archive at archivePath and returns a ZipFS rooted at
+// the given in-archive directory.
+func NewZipFS(archivePath string, root string) (*ZipFS, error) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	root = strings.TrimSuffix(root, "/")
+	return &ZipFS{
+		Reader:     &zr.Reader,
+		Root:       root,
+		FileSystem: http.FS(zipSubFS{&zr.Reader, root}),
+	}, nil
+}
+
+func (z *ZipFS) Open(name string) (http.File, error) {
+	return z.FileSystem.Open(name)
+}
+
+func (z *ZipFS) Stat(name string) (os.FileInfo, error) {
+	f, err := z.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Stat()
+}
+
+func (z *ZipFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(zipSubFS{z.Reader, z.Root}, strings.TrimPrefix(name, "/"))
+}
+
+func (z *ZipFS) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(zipSubFS{z.Reader, z.Root}, strings.TrimPrefix(name, "/"))
+}
+
+// zipSubFS adapts a *zip.Reader rooted at some archive-internal directory
+// to fs.FS so it can be passed to http.FS and the fs.ReadDir/fs.ReadFile helpers.
+type zipSubFS struct {
+	reader *zip.Reader
+	root   string
+}
+
+func (z zipSubFS) Open(name string) (fs.File, error) {
+	if name == "." || name == "" {
+		name = z.root
+	} else if z.root == "" {
+		name = strings.TrimPrefix(name, "/")
+	} else {
+		name = z.root + "/" + name
+	}
+	return z.reader.Open(name)
+}
+
+// archiveCandidatePath returns the zip archive that should back desiredPath,
+// either because desiredPath itself ends in .zip or a sibling
+// .zk-root.zip file sits alongside it. Returns "" if neither applies.
+func archiveCandidatePath(desiredPath string) string {
+	if strings.HasSuffix(desiredPath, ".zip") {
+		return desiredPath
+	}
+	sibling := filepath.Join(desiredPath, ZK_ROOT_ARCHIVE_FILENAME)
+	if _, err := os.Stat(sibling); err == nil {
+		return sibling
+	}
+	return ""
+}
+
+// openZkRootArchive opens the zip archive at archivePath and locates the
+// .zk-root directory inside it, returning a ZipFS rooted there.
+func openZkRootArchive(archivePath string) (*ZipFS, error) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	rootDir := ""
+	found := false
+	for _, f := range zr.File {
+		name := strings.TrimSuffix(f.Name, "/")
+		if name == ZK_ROOT_FILENAME || strings.HasSuffix(name, "/"+ZK_ROOT_FILENAME) {
+			rootDir = filepath.Dir(name)
+			if rootDir == "." {
+				rootDir = ""
+			}
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, errors.New(".zk-root cannot be found in archive")
+	}
+
+	return NewZipFS(archivePath, rootDir)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestZip writes a zip archive with the given name -> content entries
+// to a temp file and returns its path.
+func writeTestZip(t *testing.T, entries map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "notes.zip")
+	f, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for name, content := range entries {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return zipPath
+}
+
+func TestZipFSTopLevelRoot(t *testing.T) {
+	zipPath := writeTestZip(t, map[string]string{
+		".zk-root":   "",
+		"about.html": "<html><body>about</body></html>",
+	})
+
+	zipFS, err := openZkRootArchive(zipPath)
+	if err != nil {
+		t.Fatalf("openZkRootArchive: %v", err)
+	}
+
+	content, err := zipFS.ReadFile("/about.html")
+	if err != nil {
+		t.Fatalf("ReadFile(/about.html): %v", err)
+	}
+	if !bytes.Contains(content, []byte("about")) {
+		t.Fatalf("unexpected content: %s", content)
+	}
+
+	f, err := zipFS.Open("/about.html")
+	if err != nil {
+		t.Fatalf("Open(/about.html): %v", err)
+	}
+	defer f.Close()
+	openedContent, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("reading opened file: %v", err)
+	}
+	if !bytes.Contains(openedContent, []byte("about")) {
+		t.Fatalf("unexpected content from Open: %s", openedContent)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchRecursive adds root and every subdirectory beneath it to watcher.
+// fsnotify watches are not recursive, so every directory in the tree has to
+// be registered individually, and any directory created afterwards has to
+// be added as it appears.
+func watchRecursive(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+}
+
+// trackNewDirectories watches for Create events reported for directories
+// and adds them to watcher so files written inside them are seen too.
+func trackNewDirectories(watcher *fsnotify.Watcher, event fsnotify.Event) {
+	if event.Op&fsnotify.Create == 0 {
+		return
+	}
+	if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+		watcher.Add(event.Name)
+	}
+}
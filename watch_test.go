@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestWatchRecursiveSeesSubdirectoryEdits(t *testing.T) {
+	dir := t.TempDir()
+	subdir := filepath.Join(dir, "notes")
+	if err := os.Mkdir(subdir, 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatalf("NewWatcher: %v", err)
+	}
+	defer watcher.Close()
+	if err := watchRecursive(watcher, dir); err != nil {
+		t.Fatalf("watchRecursive: %v", err)
+	}
+
+	notePath := filepath.Join(subdir, "a.html")
+	if err := os.WriteFile(notePath, []byte("hi"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case event := <-watcher.Events:
+		if event.Name != notePath {
+			t.Fatalf("expected event for %s, got %s", notePath, event.Name)
+		}
+	case err := <-watcher.Errors:
+		t.Fatalf("watcher error: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an fsnotify event from a subdirectory")
+	}
+}